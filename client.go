@@ -47,6 +47,18 @@ type Client interface {
 	// Messages published to those topics from other clients will no longer be
 	// received.
 	Unsubscribe(topics ...string) Result
+	// PauseStore blocks writes to the local message store until ResumeStore is called,
+	// e.g. to back up the store directory. Publish and other calls that persist
+	// messages wait while the store is paused. The connection read loop also stops
+	// at the next inbound message it persists, including acknowledgements, so a long
+	// pause can trip the connection deadline or keepalive and drop the connection.
+	PauseStore()
+	// ResumeStore releases writes to the local message store blocked by PauseStore.
+	ResumeStore()
+	// MigrateStore copies the local message store into a new store at destPath with
+	// size buffer size. Open a new client with the same client ID using WithStorePath(destPath)
+	// and WithStoreSize(size) to use it.
+	MigrateStore(destPath string, size int) error
 }
 type client struct {
 	opts       *options
@@ -100,11 +112,7 @@ func NewClient(target, clientID string, opts ...Options) (Client, error) {
 	c.callbacks[0] = c.opts.defaultMessageHandler
 
 	// Open database connection
-	path := c.opts.storePath
-	if clientID != "" {
-		path = path + "/" + clientID
-	}
-	if err := store.Open(path, int64(c.opts.storeSize), false); err != nil {
+	if err := store.Open(storeDir(c.opts.storePath, clientID), int64(c.opts.storeSize), false); err != nil {
 		return nil, err
 	}
 
@@ -459,6 +467,38 @@ func (c *client) Unsubscribe(topics ...string) Result {
 	return r
 }
 
+// PauseStore blocks writes to the local message store until ResumeStore is called.
+// It returns once in-flight writes have completed. While paused, the connection read
+// loop blocks on the next PUBLISH, NOTIFY, ACKNOWLEDGE or COMPLETE it receives, as
+// storeInbound persists or deletes it before the message is handled. No further
+// messages are read until ResumeStore, so keep pauses well below the connection
+// deadline and keepalive timeout.
+func (c *client) PauseStore() {
+	store.Pause()
+}
+
+// ResumeStore releases writes to the local message store blocked by PauseStore.
+func (c *client) ResumeStore() {
+	store.Resume()
+}
+
+// MigrateStore copies the local message store into a new store with size buffer size.
+// The store is written to destPath/clientID, or to destPath if the client has no client ID,
+// the directory NewClient opens for WithStorePath(destPath). It refuses an existing
+// directory and a size smaller than the current store size. Writes made during
+// MigrateStore may not be copied, use PauseStore to quiesce writes first.
+func (c *client) MigrateStore(destPath string, size int) error {
+	return store.Migrate(storeDir(destPath, c.opts.clientID), int64(size))
+}
+
+// storeDir returns the directory of the message store for the client ID under path.
+func storeDir(path, clientID string) string {
+	if clientID != "" {
+		return path + "/" + clientID
+	}
+	return path
+}
+
 // Load all stored messages and resend them to ensure DeliveryMode even after an application crash.
 func (c *client) resume(prefix uint32, subscription bool) {
 	keys := store.Log.Keys(prefix)
@@ -520,7 +560,7 @@ func (c *client) resume(prefix uint32, subscription bool) {
 					c.send <- &MessageAndResult{m: ctrl}
 				}
 			default:
-				store.Log.Delete(k)
+				c.deleteStored(k)
 			}
 		} else {
 			switch msg.Type() {
@@ -532,12 +572,20 @@ func (c *client) resume(prefix uint32, subscription bool) {
 					c.recv <- msg
 				}
 			default:
-				store.Log.Delete(k)
+				c.deleteStored(k)
 			}
 		}
 	}
 }
 
+// deleteStored deletes a stored message that no longer needs to be resumed. A message
+// already deleted is not an error.
+func (c *client) deleteStored(key uint64) {
+	if err := store.Log.Delete(key); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Println("client.resume: ", err)
+	}
+}
+
 // TimeNow returns current wall time in UTC rounded to milliseconds.
 func TimeNow() time.Time {
 	return time.Now().UTC().Round(time.Millisecond)
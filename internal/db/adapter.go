@@ -1,5 +1,14 @@
 package adapter
 
+import "errors"
+
+var (
+	// ErrClosed is returned when the adapter is used while it is not open.
+	ErrClosed = errors.New("store adapter is closed")
+	// ErrNotFound is returned when deleting a message for a key that does not exist.
+	ErrNotFound = errors.New("store adapter: message not found")
+)
+
 // Adapter represents a message storage contract that message storage provides
 // must fulfill.
 type Adapter interface {
@@ -19,12 +28,30 @@ type Adapter interface {
 	PutMessage(key uint64, payload []byte) error
 
 	// GetMessage performs a query and attempts to fetch message for the given key
+	// the returned slice is a copy owned by the caller.
 	GetMessage(key uint64) ([]byte, error)
 
 	// DeleteMessage is used to delete message.
-	// it returns an error if some error was encountered during delete.
+	// it returns ErrNotFound if there is no message for the key, or an error
+	// if some other error was encountered during delete.
 	DeleteMessage(key uint64) error
 
+	// DeleteMessages is used to delete messages for multiple keys.
+	// keys without a message are skipped. it keeps deleting the remaining keys
+	// if a delete fails, and returns number of messages deleted and the first
+	// error encountered.
+	DeleteMessages(keys []uint64) (int, error)
+
+	// Pause blocks new writes until Resume is called, or the adapter is closed or reopened.
+	Pause()
+	// Resume releases writes blocked by Pause.
+	Resume()
+
+	// Migrate copies all messages into a new store at destPath with newSize buffer size.
+	// it refuses an existing destPath, a destPath inside the store, and a newSize smaller
+	// than the store size.
+	Migrate(destPath string, newSize int64) error
+
 	// Keys performs a query and attempts to fetch all keys.
 	Keys() []uint64
 }
@@ -2,7 +2,14 @@ package adapter
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	dbadapter "github.com/unit-io/unitdb-go/internal/db"
 	"github.com/unit-io/unitdb-go/internal/store"
 	"github.com/unit-io/unitdb/memdb"
 )
@@ -14,49 +21,106 @@ const (
 	logPostfix  = ".log"
 )
 
+// Adapter states.
+const (
+	stateClosed = iota
+	stateOpen
+)
+
+// memdb does not export its lookup errors, so a missing entry is matched by message.
+const memdbEntryDoesNotExist = "Entry does not exist in memdb"
+
+// isNotFound returns true if err is memdb's error for a missing entry.
+func isNotFound(err error) bool {
+	return err != nil && err.Error() == memdbEntryDoesNotExist
+}
+
 // adapter represents an SSD-optimized store.
 type adapter struct {
 	version int
-	db      *memdb.DB // The underlying database to store messages.
+	mu      sync.RWMutex // held for reading while db is used, and for writing to open or close it.
+	state   int          // stateClosed or stateOpen, guarded by mu.
+	open    int32        // 1 while state is stateOpen, read atomically by IsOpen.
+	db      *memdb.DB    // The underlying database to store messages.
+	path    string       // The path db was opened with.
+	size    int64        // The buffer size db was opened with.
+
+	// getMu serializes lookups, as memdb caches them in maps it does not lock.
+	getMu sync.Mutex
+	// deleteMu serializes deletes so a key is reported deleted only once.
+	deleteMu sync.Mutex
+
+	// pause
+	pauseMu   sync.Mutex
+	pauseCond *sync.Cond // broadcast when paused or writers changes.
+	paused    bool
+	writers   int // number of in-flight writes.
+}
+
+func newAdapter() *adapter {
+	a := &adapter{}
+	a.pauseCond = sync.NewCond(&a.pauseMu)
+	return a
 }
 
 // Open initializes database connection
 func (a *adapter) Open(path string, size int64, reset bool) error {
-	if a.db != nil {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state != stateClosed {
 		return errors.New("unitdb adapter is already connected")
 	}
 
-	var err error
 	// Attempt to open the database
 	var opts memdb.Options
 	if reset {
 		opts = memdb.WithLogReset()
 	}
 
-	a.db, err = memdb.Open(opts, memdb.WithLogFilePath(path), memdb.WithBufferSize(size))
+	db, err := memdb.Open(opts, memdb.WithLogFilePath(path), memdb.WithBufferSize(size))
 	if err != nil {
 		return err
 	}
+	a.db = db
+	a.path = path
+	a.size = size
+	a.unpause()
+	a.state = stateOpen
+	atomic.StoreInt32(&a.open, 1)
 
 	return nil
 }
 
-// Close closes the underlying database connection
+// Close closes the underlying database connection. It waits for calls using the
+// database to return, and returns once the database is closed.
 func (a *adapter) Close() error {
-	var err error
-	if a.db != nil {
-		err = a.db.Close()
-		a.db = nil
-		a.version = -1
-
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state != stateOpen {
+		return nil
 	}
+	err := a.db.Close()
+	a.db = nil
+	// Release writes blocked by Pause, they return ErrClosed.
+	a.unpause()
+	a.state = stateClosed
+	atomic.StoreInt32(&a.open, 0)
+
 	return err
 }
 
 // IsOpen returns true if connection to database has been established. It does not check if
 // connection is actually live.
 func (a *adapter) IsOpen() bool {
-	return a.db != nil
+	return atomic.LoadInt32(&a.open) == 1
+}
+
+// ok returns ErrClosed if the adapter is not open. Callers must hold mu.
+func (a *adapter) ok() error {
+	if a.state != stateOpen {
+		return dbadapter.ErrClosed
+	}
+	return nil
 }
 
 // GetName returns string that adapter uses to register itself with store.
@@ -66,6 +130,13 @@ func (a *adapter) GetName() string {
 
 // PutMessage appends the messages to the store.
 func (a *adapter) PutMessage(key uint64, payload []byte) error {
+	a.beginWrite()
+	defer a.endWrite()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return err
+	}
 	if _, err := a.db.Put(key, payload); err != nil {
 		return err
 	}
@@ -73,28 +144,208 @@ func (a *adapter) PutMessage(key uint64, payload []byte) error {
 }
 
 // GetMessage performs a query and attempts to fetch message for the given key
+// The returned slice is a copy owned by the caller, as memdb.Get copies the message.
 func (a *adapter) GetMessage(key uint64) (matches []byte, err error) {
-	matches, err = a.db.Get(key)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return nil, err
+	}
+	matches, err = a.get(key)
 	if err != nil {
 		return nil, err
 	}
 	return matches, nil
 }
 
+// get fetches message for the given key from memdb. Callers must hold mu.
+func (a *adapter) get(key uint64) ([]byte, error) {
+	a.getMu.Lock()
+	defer a.getMu.Unlock()
+	return a.db.Get(key)
+}
+
 // DeleteMessage deletes message from memdb store.
+// It returns dbadapter.ErrNotFound if there is no message for the key.
 func (a *adapter) DeleteMessage(key uint64) error {
+	a.beginWrite()
+	defer a.endWrite()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return err
+	}
+	a.deleteMu.Lock()
+	defer a.deleteMu.Unlock()
 	if err := a.db.Delete(key); err != nil {
+		if isNotFound(err) {
+			return dbadapter.ErrNotFound
+		}
 		return err
 	}
 	return nil
 }
 
+// DeleteMessages deletes messages for the given keys from memdb store. Keys without
+// a message are skipped. A failed delete does not stop the remaining keys from being
+// deleted; it returns the number of messages deleted and the first error encountered.
+// memdb has no batched delete, so each key is still deleted with its own memdb call
+// and WAL record; this only saves the per-call overhead of the store.
+func (a *adapter) DeleteMessages(keys []uint64) (deleted int, err error) {
+	a.beginWrite()
+	defer a.endWrite()
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return 0, err
+	}
+	a.deleteMu.Lock()
+	defer a.deleteMu.Unlock()
+	for _, key := range keys {
+		if delErr := a.db.Delete(key); delErr != nil {
+			if isNotFound(delErr) {
+				continue
+			}
+			if err == nil {
+				err = delErr
+			}
+			continue
+		}
+		deleted++
+	}
+	return deleted, err
+}
+
+// beginWrite waits while writes are paused and then registers an in-flight write.
+func (a *adapter) beginWrite() {
+	a.pauseMu.Lock()
+	for a.paused {
+		a.pauseCond.Wait()
+	}
+	a.writers++
+	a.pauseMu.Unlock()
+}
+
+// endWrite unregisters an in-flight write.
+func (a *adapter) endWrite() {
+	a.pauseMu.Lock()
+	a.writers--
+	if a.writers == 0 {
+		a.pauseCond.Broadcast()
+	}
+	a.pauseMu.Unlock()
+}
+
+// Pause blocks new writes until Resume is called. It waits for in-flight writes
+// to complete, so the store is quiesced once Pause returns, unless Resume is called
+// meanwhile. Resume never waits for in-flight writes. Close and Open also release
+// the pause, so a paused adapter never stays blocked across a reopen.
+func (a *adapter) Pause() {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	a.paused = true
+	for a.paused && a.writers > 0 {
+		a.pauseCond.Wait()
+	}
+}
+
+// Resume releases writes blocked by Pause.
+func (a *adapter) Resume() {
+	a.unpause()
+}
+
+// unpause releases writes blocked by Pause and wakes a Pause waiting for writers.
+func (a *adapter) unpause() {
+	a.pauseMu.Lock()
+	a.paused = false
+	a.pauseCond.Broadcast()
+	a.pauseMu.Unlock()
+}
+
 // Keys performs a query and attempts to fetch all keys.
 func (a *adapter) Keys() []uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.ok() != nil {
+		return nil
+	}
 	return a.db.Keys()
 }
 
+// Migrate copies all messages into a new store at destPath opened with newSize buffer size.
+// It refuses a newSize smaller than the size of the open store, a destPath inside the open
+// store, and an existing destPath, as the destination log is reset. If the copy fails,
+// destPath is removed. Writes made during Migrate may not be copied, use Pause to quiesce
+// writes first.
+func (a *adapter) Migrate(destPath string, newSize int64) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return err
+	}
+
+	if newSize < a.size {
+		return fmt.Errorf("unitdb adapter: cannot migrate to size %d smaller than store size %d", newSize, a.size)
+	}
+	if withinPath(a.path, destPath) {
+		return errors.New("unitdb adapter: cannot migrate store into itself")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("unitdb adapter: migrate destination %s already exists", destPath)
+	}
+
+	dest, err := memdb.Open(memdb.WithLogReset(), memdb.WithLogFilePath(destPath), memdb.WithBufferSize(newSize))
+	if err != nil {
+		os.RemoveAll(destPath)
+		return err
+	}
+	err = a.copyTo(dest)
+	if cerr := dest.Close(); cerr != nil {
+		if err == nil {
+			err = cerr
+		} else {
+			err = fmt.Errorf("%w (closing destination: %v)", err, cerr)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(destPath)
+	}
+
+	return err
+}
+
+// copyTo puts all messages of the adapter into dest. Callers must hold mu.
+func (a *adapter) copyTo(dest *memdb.DB) error {
+	for _, key := range a.db.Keys() {
+		payload, err := a.get(key)
+		if isNotFound(err) {
+			continue // deleted since Keys was called.
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := dest.Put(key, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withinPath returns true if path is root or a location inside root.
+func withinPath(root, path string) bool {
+	absRoot, errRoot := filepath.Abs(root)
+	absPath, errPath := filepath.Abs(path)
+	if errRoot != nil || errPath != nil {
+		absRoot, absPath = filepath.Clean(root), filepath.Clean(path)
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func init() {
-	adp := &adapter{}
+	adp := newAdapter()
 	store.RegisterAdapter(adapterName, adp)
 }
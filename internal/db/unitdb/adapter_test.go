@@ -0,0 +1,367 @@
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	dbadapter "github.com/unit-io/unitdb-go/internal/db"
+)
+
+const testStoreSize = 1 << 20
+
+// DiffEntry describes a key whose message differs between two adapters.
+// Left or Right is nil if the key is missing from that adapter.
+type DiffEntry struct {
+	Key   uint64
+	Left  []byte
+	Right []byte
+}
+
+// Diff compares all keys and messages of the adapter with other adapter and returns
+// keys that are present in only one of them or have different payloads, sorted by key.
+// Each adapter is read under its own lock in turn, never both at once, so Diffs running
+// in opposite directions cannot deadlock.
+func (a *adapter) Diff(other *adapter) ([]DiffEntry, error) {
+	if other == nil {
+		return nil, errors.New("unitdb adapter: nil adapter to compare")
+	}
+	left, err := a.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	right, err := other.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []DiffEntry
+	for key, l := range left {
+		r, ok := right[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, DiffEntry{Key: key, Left: l})
+		case !bytes.Equal(l, r):
+			diffs = append(diffs, DiffEntry{Key: key, Left: l, Right: r})
+		}
+		delete(right, key)
+	}
+	for key, r := range right {
+		diffs = append(diffs, DiffEntry{Key: key, Right: r})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Key < diffs[j].Key })
+	return diffs, nil
+}
+
+// snapshot returns all messages of the adapter by key. Keys deleted while it runs are skipped.
+func (a *adapter) snapshot() (map[uint64][]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if err := a.ok(); err != nil {
+		return nil, err
+	}
+	msgs := make(map[uint64][]byte)
+	for _, key := range a.db.Keys() {
+		payload, err := a.get(key)
+		if isNotFound(err) {
+			continue // deleted since Keys was called.
+		}
+		if err != nil {
+			return nil, err
+		}
+		msgs[key] = payload
+	}
+	return msgs, nil
+}
+
+func openTestAdapter(t *testing.T) *adapter {
+	a := newAdapter()
+	if err := a.Open(t.TempDir(), testStoreSize, true); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestDeleteMessages(t *testing.T) {
+	a := openTestAdapter(t)
+	for i := uint64(1); i <= 3; i++ {
+		if err := a.PutMessage(i, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := a.DeleteMessages([]uint64{1, 2, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 messages deleted; got %d", deleted)
+	}
+	if _, err := a.GetMessage(3); err != nil {
+		t.Fatalf("expected message 3 to be kept; got %v", err)
+	}
+	if _, err := a.GetMessage(1); err == nil {
+		t.Fatal("expected message 1 to be deleted")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := openTestAdapter(t)
+	b := openTestAdapter(t)
+
+	puts := []struct {
+		adp     *adapter
+		key     uint64
+		payload string
+	}{
+		{a, 1, "same"},
+		{b, 1, "same"},
+		{a, 2, "left"},
+		{b, 2, "right"},
+		{a, 3, "only left"},
+		{b, 4, "only right"},
+	}
+	for _, p := range puts {
+		if err := p.adp.PutMessage(p.key, []byte(p.payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := a.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiffEntry{
+		{Key: 2, Left: []byte("left"), Right: []byte("right")},
+		{Key: 3, Left: []byte("only left")},
+		{Key: 4, Right: []byte("only right")},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("expected %v; got %v", want, diffs)
+	}
+
+	if _, err := a.Diff(nil); err == nil {
+		t.Fatal("expected error comparing with nil adapter")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	a := openTestAdapter(t)
+	a.Pause()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.PutMessage(1, []byte("msg"))
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected PutMessage to block while paused; returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	a.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected PutMessage to continue after Resume")
+	}
+
+	if _, err := a.GetMessage(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPauseReleasedByClose(t *testing.T) {
+	a := newAdapter()
+	path := t.TempDir()
+	if err := a.Open(path, testStoreSize, true); err != nil {
+		t.Fatal(err)
+	}
+	a.Pause()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.PutMessage(1, []byte("msg"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != dbadapter.ErrClosed {
+			t.Fatalf("expected ErrClosed after Close; got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Close to release writes blocked by Pause")
+	}
+
+	// Pause on a closed adapter must not block writes after the next Open.
+	a.Pause()
+	if err := a.Open(path, testStoreSize, false); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	go func() {
+		done <- a.PutMessage(2, []byte("msg"))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected writes not to be paused after Open")
+	}
+}
+
+func TestCloseWhileWriting(t *testing.T) {
+	a := newAdapter()
+	if err := a.Open(t.TempDir(), testStoreSize, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := uint64(0); ; n++ {
+				key := uint64(i)<<32 + n
+				if err := a.PutMessage(key, []byte("msg")); err != nil {
+					errs <- err
+					return
+				}
+				a.GetMessage(key)
+				a.Keys()
+			}
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != dbadapter.ErrClosed {
+			t.Fatalf("expected ErrClosed after Close; got %v", err)
+		}
+	}
+
+	if a.IsOpen() {
+		t.Fatal("expected adapter to be closed")
+	}
+	if err := a.PutMessage(1, []byte("msg")); err != dbadapter.ErrClosed {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+	if _, err := a.GetMessage(1); err != dbadapter.ErrClosed {
+		t.Fatalf("expected ErrClosed; got %v", err)
+	}
+}
+
+func TestOpenTwice(t *testing.T) {
+	a := openTestAdapter(t)
+	if err := a.Open(t.TempDir(), testStoreSize, true); err == nil {
+		t.Fatal("expected error opening an open adapter")
+	}
+}
+
+func TestDeleteMessageNotFound(t *testing.T) {
+	a := openTestAdapter(t)
+	if err := a.DeleteMessage(1); !errors.Is(err, dbadapter.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound; got %v", err)
+	}
+
+	if err := a.PutMessage(1, []byte("msg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteMessage(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteMessage(1); !errors.Is(err, dbadapter.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice; got %v", err)
+	}
+}
+
+func TestPutTwiceLastWins(t *testing.T) {
+	a := newAdapter()
+	path := t.TempDir()
+	if err := a.Open(path, testStoreSize, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.PutMessage(1, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.PutMessage(1, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Open(path, testStoreSize, false); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	payload, err := a.GetMessage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "second" {
+		t.Fatalf("expected %q after reopen; got %q", "second", payload)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	a := openTestAdapter(t)
+	for i := uint64(1); i <= 10; i++ {
+		if err := a.PutMessage(i, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.Migrate(a.path, 2*testStoreSize); err == nil {
+		t.Fatal("expected error migrating store onto itself")
+	}
+	if err := a.Migrate(filepath.Join(a.path, "nested"), 2*testStoreSize); err == nil {
+		t.Fatal("expected error migrating store into itself")
+	}
+	if err := a.Migrate(t.TempDir(), 2*testStoreSize); err == nil {
+		t.Fatal("expected error migrating onto an existing path")
+	}
+	destPath := filepath.Join(t.TempDir(), "dest")
+	if err := a.Migrate(destPath, testStoreSize/2); err == nil {
+		t.Fatal("expected error migrating to a smaller size")
+	}
+
+	if err := a.Migrate(destPath, 2*testStoreSize); err != nil {
+		t.Fatal(err)
+	}
+
+	b := newAdapter()
+	if err := b.Open(destPath, 2*testStoreSize, false); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	diffs, err := a.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("expected migrated store to match; got %v", diffs)
+	}
+}
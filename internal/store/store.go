@@ -11,6 +11,9 @@ import (
 
 var adp adapter.Adapter
 
+// ErrNotFound is returned by Delete if there is no message for the key.
+var ErrNotFound = adapter.ErrNotFound
+
 func open(path string, size int64, reset bool) error {
 	if adp == nil {
 		return errors.New("store: database adapter is missing")
@@ -51,6 +54,29 @@ func IsOpen() bool {
 	return false
 }
 
+// Pause blocks writes to persistent storage until Resume is called.
+func Pause() {
+	if adp != nil {
+		adp.Pause()
+	}
+}
+
+// Resume releases writes to persistent storage blocked by Pause.
+func Resume() {
+	if adp != nil {
+		adp.Resume()
+	}
+}
+
+// Migrate copies all messages into a new store at destPath with size buffer size,
+// e.g. to move to a larger store. It refuses an existing destPath.
+func Migrate(destPath string, size int64) error {
+	if adp == nil {
+		return errors.New("store: database adapter is missing")
+	}
+	return adp.Migrate(destPath, size)
+}
+
 // GetAdapterName returns the name of the current adater.
 func GetAdapterName() string {
 	if adp != nil {
@@ -185,17 +211,15 @@ func (l *MessageLog) Keys(prefix uint32) []uint64 {
 }
 
 // Delete is used to delete message.
-func (l *MessageLog) Delete(key uint64) {
-	adp.DeleteMessage(key)
+// It returns ErrNotFound if there is no message for the key.
+func (l *MessageLog) Delete(key uint64) error {
+	return adp.DeleteMessage(key)
 }
 
 // Reset removes all keys with the prefix from the store.
 func (l *MessageLog) Reset(prefix uint32) {
-	keys := adp.Keys()
-	for _, key := range keys {
-		if evalPrefix(prefix, key) {
-			adp.DeleteMessage(key)
-		}
+	if _, err := adp.DeleteMessages(l.Keys(prefix)); err != nil {
+		fmt.Println(err)
 	}
 }
 